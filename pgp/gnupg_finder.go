@@ -2,22 +2,73 @@ package pgp
 
 import (
 	"errors"
+	"fmt"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
-// Skip GPG version check for GPG 1.x
-var skipGPGVersionCheck bool
+// GPGVersion stores the parsed result of `gpg --version`: the major.minor.patch
+// triple, the bundled libgcrypt version, and the raw output it was parsed
+// from (kept around for diagnostics and matchers that need more than the
+// numbers, e.g. distro patch suffixes).
+type GPGVersion struct {
+	Major            int
+	Minor            int
+	Patch            int
+	LibgcryptVersion string
+	Raw              string
+}
+
+// String renders the version the way `gpg --version` itself does.
+func (v GPGVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
 
-// GPGVersion stores discovered GPG version
-//
-// 1 for 1.x, and 2 for 2.x
-type GPGVersion int
+// AtLeast reports whether v is greater than or equal to major.minor, e.g.
+// AtLeast(2, 1) to gate use of --pinentry-mode loopback.
+func (v GPGVersion) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+var (
+	gpgVersionRegexp       = regexp.MustCompile(`gpg \(GnuPG(?:/MacGPG2)?\) (\d+)\.(\d+)\.(\d+)`)
+	libgcryptVersionRegexp = regexp.MustCompile(`libgcrypt (\S+)`)
+)
+
+// parseGPGVersion parses the output of `gpg --version`.
+func parseGPGVersion(output string) (GPGVersion, error) {
+	version := GPGVersion{Raw: output}
+
+	m := gpgVersionRegexp.FindStringSubmatch(output)
+	if m == nil {
+		firstLine := strings.SplitN(output, "\n", 2)[0]
+		return version, fmt.Errorf("unable to parse gpg version from output: %q", firstLine)
+	}
+
+	version.Major, _ = strconv.Atoi(m[1])
+	version.Minor, _ = strconv.Atoi(m[2])
+	version.Patch, _ = strconv.Atoi(m[3])
+
+	if lm := libgcryptVersionRegexp.FindStringSubmatch(output); lm != nil {
+		version.LibgcryptVersion = lm[1]
+	}
+
+	return version, nil
+}
 
 // GPGFinder implement search for gpg executables and returns version of discovered executables
 type GPGFinder interface {
 	FindGPG() (gpg string, version GPGVersion, err error)
 	FindGPGV() (gpgv string, version GPGVersion, err error)
+	// FindGPGVersion runs `cmd --version` and parses its reported version,
+	// without trying the candidate names or checking the generation marker
+	// used by FindGPG/FindGPGV.
+	FindGPGVersion(cmd string) (GPGVersion, error)
 }
 
 type pathGPGFinder struct {
@@ -27,7 +78,6 @@ type pathGPGFinder struct {
 	errorMessage string
 
 	expectedVersionSubstring string
-	version                  GPGVersion
 }
 
 type iteratingGPGFinder struct {
@@ -50,7 +100,6 @@ func GPG1Finder() GPGFinder {
 		gpgvNames:                []string{"gpgv", "gpgv1"},
 		expectedVersionSubstring: "(GnuPG) 1.",
 		errorMessage:             "Couldn't find a suitable gpg executable. Make sure gnupg1 is available as either gpg(v) or gpg(v)1 in $PATH",
-		version:                  1,
 	}
 }
 
@@ -61,20 +110,18 @@ func GPG2Finder() GPGFinder {
 		gpgvNames:                []string{"gpgv", "gpgv2"},
 		expectedVersionSubstring: "(GnuPG) 2.",
 		errorMessage:             "Couldn't find a suitable gpg executable. Make sure gnupg2 is available as either gpg(v) or gpg(v)2 in $PATH",
-		version:                  2,
 	}
 }
 
 func (pgf *pathGPGFinder) FindGPG() (gpg string, version GPGVersion, err error) {
 	for _, cmd := range pgf.gpgNames {
-		if cliVersionCheck(cmd, pgf.expectedVersionSubstring) {
+		if v, ok := cliVersionCheck(cmd, pgf.expectedVersionSubstring); ok {
 			gpg = cmd
+			version = v
 			break
 		}
 	}
 
-	version = pgf.version
-
 	if gpg == "" {
 		err = errors.New(pgf.errorMessage)
 	}
@@ -84,14 +131,13 @@ func (pgf *pathGPGFinder) FindGPG() (gpg string, version GPGVersion, err error)
 
 func (pgf *pathGPGFinder) FindGPGV() (gpgv string, version GPGVersion, err error) {
 	for _, cmd := range pgf.gpgvNames {
-		if cliVersionCheck(cmd, pgf.expectedVersionSubstring) {
+		if v, ok := cliVersionCheck(cmd, pgf.expectedVersionSubstring); ok {
 			gpgv = cmd
+			version = v
 			break
 		}
 	}
 
-	version = pgf.version
-
 	if gpgv == "" {
 		err = errors.New(pgf.errorMessage)
 	}
@@ -99,6 +145,10 @@ func (pgf *pathGPGFinder) FindGPGV() (gpgv string, version GPGVersion, err error
 	return
 }
 
+func (pgf *pathGPGFinder) FindGPGVersion(cmd string) (GPGVersion, error) {
+	return gpgVersionFromCommand(cmd)
+}
+
 func (it *iteratingGPGFinder) FindGPG() (gpg string, version GPGVersion, err error) {
 	for _, finder := range it.finders {
 		gpg, version, err = finder.FindGPG()
@@ -112,9 +162,9 @@ func (it *iteratingGPGFinder) FindGPG() (gpg string, version GPGVersion, err err
 	return
 }
 
-func (it *iteratingGPGFinder) FindGPGV() (gpg string, version GPGVersion, err error) {
+func (it *iteratingGPGFinder) FindGPGV() (gpgv string, version GPGVersion, err error) {
 	for _, finder := range it.finders {
-		gpg, version, err = finder.FindGPGV()
+		gpgv, version, err = finder.FindGPGV()
 		if err == nil {
 			return
 		}
@@ -125,10 +175,37 @@ func (it *iteratingGPGFinder) FindGPGV() (gpg string, version GPGVersion, err er
 	return
 }
 
-func cliVersionCheck(cmd string, marker string) bool {
+func (it *iteratingGPGFinder) FindGPGVersion(cmd string) (GPGVersion, error) {
+	return gpgVersionFromCommand(cmd)
+}
+
+func gpgVersionFromCommand(cmd string) (GPGVersion, error) {
+	output, err := exec.Command(cmd, "--version").CombinedOutput()
+	if err != nil {
+		return GPGVersion{}, err
+	}
+
+	return parseGPGVersion(string(output))
+}
+
+// cliVersionCheck runs `cmd --version`, confirms the output matches marker
+// (the generation marker, e.g. "(GnuPG) 2.") and, if so, parses the full
+// version out of the same output.
+func cliVersionCheck(cmd string, marker string) (GPGVersion, bool) {
 	output, err := exec.Command(cmd, "--version").CombinedOutput()
 	if err != nil {
-		return false
+		return GPGVersion{}, false
+	}
+
+	text := string(output)
+	if !strings.Contains(text, marker) {
+		return GPGVersion{}, false
+	}
+
+	version, err := parseGPGVersion(text)
+	if err != nil {
+		return GPGVersion{}, false
 	}
-	return strings.Contains(string(output), marker)
+
+	return version, true
 }
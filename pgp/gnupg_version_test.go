@@ -0,0 +1,75 @@
+package pgp
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type GPGVersionSuite struct{}
+
+var _ = Suite(&GPGVersionSuite{})
+
+var gpgVersionFixtures = []struct {
+	output  string
+	version GPGVersion
+}{
+	{
+		output: "gpg (GnuPG) 1.4.23\n" +
+			"Copyright (C) 2019 Free Software Foundation, Inc.\n" +
+			"License GPLv3+: GNU GPL version 3 or later <https://gnu.org/licenses/gpl.html>\n",
+		version: GPGVersion{Major: 1, Minor: 4, Patch: 23},
+	},
+	{
+		output: "gpg (GnuPG) 2.0.22\n" +
+			"libgcrypt 1.5.4\n" +
+			"Copyright (C) 2013 Free Software Foundation, Inc.\n",
+		version: GPGVersion{Major: 2, Minor: 0, Patch: 22, LibgcryptVersion: "1.5.4"},
+	},
+	{
+		output: "gpg (GnuPG) 2.1.18\n" +
+			"libgcrypt 1.7.6\n" +
+			"Copyright (C) 2017 Free Software Foundation, Inc.\n",
+		version: GPGVersion{Major: 2, Minor: 1, Patch: 18, LibgcryptVersion: "1.7.6"},
+	},
+	{
+		output: "gpg (GnuPG) 2.2.27\n" +
+			"libgcrypt 1.8.7\n" +
+			"Copyright (C) 2021 Free Software Foundation, Inc.\n",
+		version: GPGVersion{Major: 2, Minor: 2, Patch: 27, LibgcryptVersion: "1.8.7"},
+	},
+	{
+		output: "gpg (GnuPG) 2.4.4\n" +
+			"libgcrypt 1.10.3\n" +
+			"Copyright (C) 2023 g10 Code GmbH\n",
+		version: GPGVersion{Major: 2, Minor: 4, Patch: 4, LibgcryptVersion: "1.10.3"},
+	},
+}
+
+func (s *GPGVersionSuite) TestParseGPGVersion(c *C) {
+	for _, fixture := range gpgVersionFixtures {
+		version, err := parseGPGVersion(fixture.output)
+		c.Assert(err, IsNil)
+		c.Assert(version.Major, Equals, fixture.version.Major)
+		c.Assert(version.Minor, Equals, fixture.version.Minor)
+		c.Assert(version.Patch, Equals, fixture.version.Patch)
+		c.Assert(version.LibgcryptVersion, Equals, fixture.version.LibgcryptVersion)
+	}
+}
+
+func (s *GPGVersionSuite) TestParseGPGVersionInvalid(c *C) {
+	_, err := parseGPGVersion("not a gpg version string\n")
+	c.Assert(err, ErrorMatches, "unable to parse gpg version.*")
+}
+
+func (s *GPGVersionSuite) TestAtLeast(c *C) {
+	v := GPGVersion{Major: 2, Minor: 1, Patch: 18}
+	c.Assert(v.AtLeast(2, 1), Equals, true)
+	c.Assert(v.AtLeast(2, 0), Equals, true)
+	c.Assert(v.AtLeast(2, 2), Equals, false)
+	c.Assert(v.AtLeast(1, 4), Equals, true)
+	c.Assert(v.AtLeast(3, 0), Equals, false)
+}
+
+func (s *GPGVersionSuite) TestString(c *C) {
+	v := GPGVersion{Major: 2, Minor: 2, Patch: 27}
+	c.Assert(v.String(), Equals, "2.2.27")
+}
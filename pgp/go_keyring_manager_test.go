@@ -0,0 +1,81 @@
+package pgp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type GoKeyringManagerSuite struct {
+	manager *GoKeyringManager
+}
+
+var _ = Suite(&GoKeyringManagerSuite{})
+
+func (s *GoKeyringManagerSuite) SetUpTest(c *C) {
+	s.manager = NewGoKeyringManager()
+}
+
+func (s *GoKeyringManagerSuite) TestGenerateListExportDelete(c *C) {
+	id, err := s.manager.GenerateKey(KeyGenParams{Name: "Aptly Test", Email: "test@example.com"})
+	c.Assert(err, IsNil)
+	c.Assert(id, Not(Equals), KeyID(""))
+
+	keys, err := s.manager.ListKeys(PublicKeyring)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 1)
+	c.Assert(keys[0].ID, Equals, id)
+	c.Assert(keys[0].UserID, Equals, "Aptly Test <test@example.com>")
+
+	armored, err := s.manager.ExportKey(id, true)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(armored), "BEGIN PGP PUBLIC KEY BLOCK"), Equals, true)
+
+	binary, err := s.manager.ExportKey(id, false)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(binary), "BEGIN PGP"), Equals, false)
+
+	c.Assert(s.manager.DeleteKey(id), IsNil)
+
+	keys, err = s.manager.ListKeys(PublicKeyring)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+}
+
+func (s *GoKeyringManagerSuite) TestImportKey(c *C) {
+	id, err := s.manager.GenerateKey(KeyGenParams{Name: "Aptly Import"})
+	c.Assert(err, IsNil)
+
+	armored, err := s.manager.ExportKey(id, true)
+	c.Assert(err, IsNil)
+
+	other := NewGoKeyringManager()
+	imported, err := other.ImportKey(bytes.NewReader(armored))
+	c.Assert(err, IsNil)
+	c.Assert(imported, DeepEquals, []KeyID{id})
+
+	keys, err := other.ListKeys(PublicKeyring)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 1)
+	c.Assert(keys[0].ID, Equals, id)
+}
+
+func (s *GoKeyringManagerSuite) TestSignAndVerifyFile(c *C) {
+	id, err := s.manager.GenerateKey(KeyGenParams{Name: "Aptly Signer"})
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+	target := filepath.Join(dir, "Release")
+	c.Assert(os.WriteFile(target, []byte("some release content\n"), 0644), IsNil)
+
+	sig := filepath.Join(dir, "Release.sig")
+	c.Assert(s.manager.SignFile(target, sig, id), IsNil)
+
+	c.Assert(s.manager.VerifyFile(target, sig), IsNil)
+
+	c.Assert(os.WriteFile(target, []byte("tampered content\n"), 0644), IsNil)
+	c.Assert(s.manager.VerifyFile(target, sig), NotNil)
+}
@@ -0,0 +1,67 @@
+package pgp
+
+import "os"
+
+// GPGContext carries the invocation details for a gpg/gpgv child process
+// that don't belong on any one operation: which homedir to use, which TTY
+// and agent socket to advertise to gpg-agent, any site-specific extra
+// arguments, and extra environment variables. It lets callers run aptly
+// under systemd with gpg-agent on a non-default socket, isolate repo
+// signing into a dedicated homedir, or pass flags like --compress-algo
+// none without resorting to environment tricks.
+type GPGContext struct {
+	// Homedir, if set, is passed to gpg/gpgv as --homedir and exported as
+	// GNUPGHOME for child processes that don't accept the flag (gpgv).
+	Homedir string
+	// TTY, if set, is exported as GPG_TTY so pinentry can find a terminal
+	// to prompt on.
+	TTY string
+	// AgentSocket, if set, is exported as GPG_AGENT_INFO so gpg can reach
+	// a gpg-agent listening on a non-default socket.
+	AgentSocket string
+	// ExtraArgs are appended to the gpg/gpgv command line before
+	// operation-specific flags, e.g. []string{"--compress-algo", "none"}.
+	ExtraArgs []string
+	// Env are additional "KEY=VALUE" entries merged into the child
+	// process's environment, taking precedence over the ones above.
+	Env []string
+}
+
+// baseArgs returns the --homedir/extra-args prefix that should come before
+// any operation-specific flags on the gpg/gpgv command line.
+func (ctx GPGContext) baseArgs() []string {
+	var args []string
+
+	if ctx.Homedir != "" {
+		args = append(args, "--homedir", ctx.Homedir)
+	}
+
+	args = append(args, ctx.ExtraArgs...)
+
+	return args
+}
+
+// childEnv returns the environment a gpg/gpgv child process should run
+// with: the current process environment, overlaid with GPG_TTY/GNUPGHOME/
+// GPG_AGENT_INFO derived from the context, overlaid with ctx.Env.
+//
+// Plain append is enough here: the result is only ever assigned to
+// exec.Cmd.Env, and os/exec deduplicates Cmd.Env in favor of the last
+// occurrence of each key before exec'ing the child, so a later entry
+// always wins regardless of what a libc getenv() call inside the child
+// would itself prefer.
+func (ctx GPGContext) childEnv() []string {
+	env := os.Environ()
+
+	if ctx.Homedir != "" {
+		env = append(env, "GNUPGHOME="+ctx.Homedir)
+	}
+	if ctx.TTY != "" {
+		env = append(env, "GPG_TTY="+ctx.TTY)
+	}
+	if ctx.AgentSocket != "" {
+		env = append(env, "GPG_AGENT_INFO="+ctx.AgentSocket)
+	}
+
+	return append(env, ctx.Env...)
+}
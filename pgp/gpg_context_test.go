@@ -0,0 +1,50 @@
+package pgp
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type GPGContextSuite struct{}
+
+var _ = Suite(&GPGContextSuite{})
+
+func (s *GPGContextSuite) TestBaseArgs(c *C) {
+	ctx := GPGContext{
+		Homedir:   "/srv/aptly/gnupg",
+		ExtraArgs: []string{"--compress-algo", "none"},
+	}
+	c.Assert(ctx.baseArgs(), DeepEquals, []string{"--homedir", "/srv/aptly/gnupg", "--compress-algo", "none"})
+}
+
+func (s *GPGContextSuite) TestBaseArgsEmpty(c *C) {
+	c.Assert(GPGContext{}.baseArgs(), HasLen, 0)
+}
+
+func (s *GPGContextSuite) TestChildEnv(c *C) {
+	ctx := GPGContext{
+		Homedir:     "/srv/aptly/gnupg",
+		TTY:         "/dev/pts/3",
+		AgentSocket: "/run/user/1000/gnupg/S.gpg-agent",
+		Env:         []string{"GNUPGHOME=/override/wins"},
+	}
+
+	env := ctx.childEnv()
+
+	assertEnvContains := func(entry string) {
+		for _, e := range env {
+			if e == entry {
+				return
+			}
+		}
+		c.Fatalf("expected env to contain %q, got %v", entry, env)
+	}
+
+	assertEnvContains("GPG_TTY=/dev/pts/3")
+	assertEnvContains("GPG_AGENT_INFO=/run/user/1000/gnupg/S.gpg-agent")
+
+	// ctx.Env is appended last. The Homedir-derived GNUPGHOME may also be
+	// present earlier in this raw slice - that's fine, since this result
+	// is only ever assigned to exec.Cmd.Env, which keeps the last
+	// occurrence of a duplicate key and discards the rest.
+	c.Assert(env[len(env)-1], Equals, "GNUPGHOME=/override/wins")
+}
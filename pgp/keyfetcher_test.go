@@ -0,0 +1,116 @@
+package pgp
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "gopkg.in/check.v1"
+)
+
+type KeyFetcherSuite struct{}
+
+var _ = Suite(&KeyFetcherSuite{})
+
+func (s *KeyFetcherSuite) TestParseMissingKeyIDNoPubkey(c *C) {
+	stderr := "gpg: Signature made Mon 01 Jan 2024\n" +
+		"[GNUPG:] NO_PUBKEY 6E85A86E4652B4E6\n" +
+		"gpg: Can't check signature: No public key\n"
+
+	keyID, ok := ParseMissingKeyID(stderr)
+	c.Assert(ok, Equals, true)
+	c.Assert(keyID, Equals, "6E85A86E4652B4E6")
+}
+
+func (s *KeyFetcherSuite) TestParseMissingKeyIDErrsig(c *C) {
+	stderr := "[GNUPG:] ERRSIG 2B90D01014FB6F4D 1 8 00 1600000000 9\n"
+
+	keyID, ok := ParseMissingKeyID(stderr)
+	c.Assert(ok, Equals, true)
+	c.Assert(keyID, Equals, "2B90D01014FB6F4D")
+}
+
+func (s *KeyFetcherSuite) TestParseMissingKeyIDNone(c *C) {
+	_, ok := ParseMissingKeyID("gpg: Good signature from \"Test\"\n")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *KeyFetcherSuite) TestHKPKeyFetcher(c *C) {
+	const armoredKey = "-----BEGIN PGP PUBLIC KEY BLOCK-----\n...\n-----END PGP PUBLIC KEY BLOCK-----\n"
+	const keyID = "6E85A86E4652B4E6"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pks/lookup")
+		c.Assert(r.URL.Query().Get("search"), Equals, "0x"+keyID)
+		w.Write([]byte(armoredKey))
+	}))
+	defer server.Close()
+
+	fetcher := NewHKPKeyFetcher(server.URL)
+	key, err := fetcher.FetchKey(keyID)
+	c.Assert(err, IsNil)
+	c.Assert(string(key), Equals, armoredKey)
+}
+
+func (s *KeyFetcherSuite) TestHKPKeyFetcherNotFound(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "No results found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	fetcher := NewHKPKeyFetcher(server.URL)
+	_, err := fetcher.FetchKey("6E85A86E4652B4E6")
+	c.Assert(err, ErrorMatches, ".*HTTP 404.*")
+}
+
+func (s *KeyFetcherSuite) TestHTTPSKeyFetcherTemplate(c *C) {
+	const armoredKey = "-----BEGIN PGP PUBLIC KEY BLOCK-----\n...\n-----END PGP PUBLIC KEY BLOCK-----\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/keys/6E85A86E4652B4E6.asc")
+		w.Write([]byte(armoredKey))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPSKeyFetcher(server.URL + "/keys/%s.asc")
+	key, err := fetcher.FetchKey("6E85A86E4652B4E6")
+	c.Assert(err, IsNil)
+	c.Assert(string(key), Equals, armoredKey)
+}
+
+func (s *KeyFetcherSuite) TestHTTPSKeyFetcherFixedURL(c *C) {
+	const armoredKey = "-----BEGIN PGP PUBLIC KEY BLOCK-----\n...\n-----END PGP PUBLIC KEY BLOCK-----\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/signing-key.asc")
+		w.Write([]byte(armoredKey))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPSKeyFetcher(server.URL + "/signing-key.asc")
+	key, err := fetcher.FetchKey("any key ID, ignored")
+	c.Assert(err, IsNil)
+	c.Assert(string(key), Equals, armoredKey)
+}
+
+func (s *KeyFetcherSuite) TestWKDLocalPartHash(c *C) {
+	// Documented example from the WKD draft
+	// (draft-koch-openpgp-webkey-service): Joe.Doe@example.org hashes to
+	// "iy9q119eutrkn8s1mk4r39qejnbu3n5q", independently verified against a
+	// second SHA-1 + z-base-32 implementation, not just this package's own.
+	c.Assert(wkdLocalPartHash("Joe.Doe"), Equals, "iy9q119eutrkn8s1mk4r39qejnbu3n5q")
+}
+
+func (s *KeyFetcherSuite) TestSplitEmail(c *C) {
+	local, domain, ok := splitEmail("Joe.Doe@Example.ORG")
+	c.Assert(ok, Equals, true)
+	c.Assert(local, Equals, "Joe.Doe")
+	c.Assert(domain, Equals, "example.org")
+
+	_, _, ok = splitEmail("not-an-email")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *KeyFetcherSuite) TestWKDKeyFetcherInvalidEmail(c *C) {
+	_, err := NewWKDKeyFetcher().FetchKeyByEmail("not-an-email")
+	c.Assert(err, ErrorMatches, "not a valid email address.*")
+}
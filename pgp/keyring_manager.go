@@ -0,0 +1,565 @@
+package pgp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// KeyID is a GPG key ID or fingerprint, as printed by gpg in --with-colons
+// output (e.g. the long key ID "F30E8CB9CDDE2AF8").
+type KeyID string
+
+// KeyringType selects which keyring a KeyringManager operation applies to.
+type KeyringType int
+
+const (
+	// PublicKeyring is the keyring holding public keys (gpg --list-keys).
+	PublicKeyring KeyringType = iota
+	// SecretKeyring is the keyring holding secret keys (gpg --list-secret-keys).
+	SecretKeyring
+)
+
+// KeyGenParams describes a key to be generated by KeyringManager.GenerateKey.
+type KeyGenParams struct {
+	Name       string
+	Email      string
+	Comment    string
+	KeyType    string // e.g. "RSA", "ed25519"
+	KeyLength  int    // bits, ignored for fixed-size key types
+	Passphrase string
+	ExpireDate string // gpg --quick-generate-key syntax, e.g. "2y", "0" for no expiry
+}
+
+// KeyInfo describes a single key found on a keyring.
+type KeyInfo struct {
+	ID          KeyID
+	Fingerprint string
+	UserID      string
+	CreatedAt   string
+	ExpiresAt   string
+}
+
+// KeyringManager curates a GPG keyring: generating, listing, exporting,
+// importing and deleting keys, plus SignFile/VerifyFile convenience
+// wrappers so callers don't need a separate Signer/Verifier for simple
+// one-off operations.
+type KeyringManager interface {
+	GenerateKey(params KeyGenParams) (KeyID, error)
+	ListKeys(keyring KeyringType) ([]KeyInfo, error)
+	ExportKey(id KeyID, armor bool) ([]byte, error)
+	ImportKey(r io.Reader) ([]KeyID, error)
+	DeleteKey(id KeyID) error
+	SignFile(path, outputPath string, id KeyID) error
+	VerifyFile(path, signaturePath string) error
+}
+
+// GnupgKeyringManager implements KeyringManager by shelling out to the gpg
+// binary located by finder. Listing, exporting, importing, deleting,
+// signing and verifying work against both GPG1 and GPG2. GenerateKey does
+// not: it needs --quick-generate-key, which only exists in GnuPG >= 2.1,
+// and returns a clear error rather than a confusing gpg failure on older
+// binaries.
+type GnupgKeyringManager struct {
+	finder  GPGFinder
+	context GPGContext
+}
+
+// NewGnupgKeyringManager returns a KeyringManager backed by the gpg binary
+// located via finder, operating on the default GnuPG homedir.
+func NewGnupgKeyringManager(finder GPGFinder) *GnupgKeyringManager {
+	return &GnupgKeyringManager{finder: finder}
+}
+
+// SetHomedir points the manager at a GnuPG homedir other than the default,
+// e.g. so repo signing keys can be curated in isolation. Only the homedir
+// changes; the rest of the manager's GPGContext is kept as it is.
+func (m *GnupgKeyringManager) SetHomedir(homedir string) {
+	m.context.Homedir = homedir
+}
+
+// SetContext replaces the manager's GPGContext wholesale, e.g. to also pin
+// a TTY, agent socket or extra gpg arguments alongside the homedir.
+func (m *GnupgKeyringManager) SetContext(ctx GPGContext) {
+	m.context = ctx
+}
+
+func (m *GnupgKeyringManager) gpgPath() (string, error) {
+	gpg, _, err := m.finder.FindGPG()
+	if err != nil {
+		return "", err
+	}
+	return gpg, nil
+}
+
+// gpgVersion returns the real version of the gpg binary this manager runs,
+// as opposed to the 1-vs-2 generation FindGPG() matched on.
+func (m *GnupgKeyringManager) gpgVersion() (GPGVersion, error) {
+	gpg, err := m.gpgPath()
+	if err != nil {
+		return GPGVersion{}, err
+	}
+	return m.finder.FindGPGVersion(gpg)
+}
+
+func (m *GnupgKeyringManager) command(args ...string) (*exec.Cmd, error) {
+	gpg, err := m.gpgPath()
+	if err != nil {
+		return nil, err
+	}
+
+	fullArgs := append([]string{"--batch", "--yes"}, m.context.baseArgs()...)
+	fullArgs = append(fullArgs, args...)
+
+	cmd := exec.Command(gpg, fullArgs...)
+	cmd.Env = m.context.childEnv()
+
+	return cmd, nil
+}
+
+// GenerateKey implements KeyringManager.
+func (m *GnupgKeyringManager) GenerateKey(params KeyGenParams) (KeyID, error) {
+	// --quick-generate-key was introduced in GnuPG 2.1; GPG1 has no
+	// scriptable equivalent (its batch key generation needs an interactive
+	// --gen-key parameter file, not a single command line), so fail clearly
+	// instead of handing gpg an option it doesn't understand.
+	version, err := m.gpgVersion()
+	if err != nil {
+		return "", err
+	}
+	if !version.AtLeast(2, 1) {
+		return "", fmt.Errorf("GenerateKey requires GnuPG >= 2.1 (--quick-generate-key), found %s", version)
+	}
+
+	// --quick-generate-key takes the key type/length as a single "algo"
+	// token understood by gpg (e.g. "rsa4096", "ed25519"); KeyType/KeyLength
+	// are combined into that token here rather than passed separately.
+	keyType := strings.ToLower(params.KeyType)
+	if keyType == "" {
+		keyType = "rsa"
+	}
+	if keyType == "rsa" {
+		keyLength := params.KeyLength
+		if keyLength == 0 {
+			keyLength = 4096
+		}
+		keyType = fmt.Sprintf("rsa%d", keyLength)
+	}
+
+	expire := params.ExpireDate
+	if expire == "" {
+		expire = "0"
+	}
+
+	uid := params.Name
+	if params.Comment != "" {
+		uid = fmt.Sprintf("%s (%s)", uid, params.Comment)
+	}
+	if params.Email != "" {
+		uid = fmt.Sprintf("%s <%s>", uid, params.Email)
+	}
+
+	args := []string{
+		"--pinentry-mode", "loopback",
+		"--passphrase", params.Passphrase,
+		"--quick-generate-key", uid, keyType, "default", expire,
+	}
+
+	cmd, err := m.command(args...)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("gpg --quick-generate-key failed: %s\n%s", err, output)
+	}
+
+	keys, err := m.ListKeys(PublicKeyring)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range keys {
+		if k.UserID == uid {
+			return k.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("key generated but could not be found in keyring afterwards")
+}
+
+// ListKeys implements KeyringManager.
+func (m *GnupgKeyringManager) ListKeys(keyring KeyringType) ([]KeyInfo, error) {
+	listFlag := "--list-keys"
+	if keyring == SecretKeyring {
+		listFlag = "--list-secret-keys"
+	}
+
+	cmd, err := m.command("--with-colons", "--fingerprint", listFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gpg %s failed: %s", listFlag, err)
+	}
+
+	return parseColonKeyListing(string(output)), nil
+}
+
+// parseColonKeyListing parses the subset of gpg --with-colons output needed
+// to populate KeyInfo: "pub"/"sec" records for the key ID and dates, "fpr"
+// for the fingerprint and "uid" for the user ID, associated with the most
+// recently seen pub/sec record.
+func parseColonKeyListing(output string) []KeyInfo {
+	var keys []KeyInfo
+	var current *KeyInfo
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "pub", "sec":
+			keys = append(keys, KeyInfo{
+				ID:        KeyID(fields[4]),
+				CreatedAt: fields[5],
+				ExpiresAt: fields[6],
+			})
+			current = &keys[len(keys)-1]
+		case "fpr":
+			if current != nil && current.Fingerprint == "" {
+				current.Fingerprint = fields[9]
+			}
+		case "uid":
+			if current != nil && current.UserID == "" {
+				current.UserID = fields[9]
+			}
+		}
+	}
+
+	return keys
+}
+
+// ExportKey implements KeyringManager.
+func (m *GnupgKeyringManager) ExportKey(id KeyID, armor bool) ([]byte, error) {
+	args := []string{"--export", string(id)}
+	if armor {
+		args = append([]string{"--armor"}, args...)
+	}
+
+	cmd, err := m.command(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gpg --export failed: %s", err)
+	}
+
+	return output, nil
+}
+
+// ImportKey implements KeyringManager.
+func (m *GnupgKeyringManager) ImportKey(r io.Reader) ([]KeyID, error) {
+	before, err := m.ListKeys(PublicKeyring)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := m.command("--import")
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = r
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg --import failed: %s\n%s", err, stderr.String())
+	}
+
+	after, err := m.ListKeys(PublicKeyring)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[KeyID]bool, len(before))
+	for _, k := range before {
+		seen[k.ID] = true
+	}
+
+	var imported []KeyID
+	for _, k := range after {
+		if !seen[k.ID] {
+			imported = append(imported, k.ID)
+		}
+	}
+
+	return imported, nil
+}
+
+// DeleteKey implements KeyringManager.
+func (m *GnupgKeyringManager) DeleteKey(id KeyID) error {
+	// In --batch mode gpg refuses to delete a key by its (possibly
+	// ambiguous) key ID and insists on the full fingerprint.
+	fingerprint, err := m.fingerprintOf(id)
+	if err != nil {
+		return err
+	}
+
+	cmd, err := m.command("--delete-secret-and-public-key", fingerprint)
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gpg --delete-secret-and-public-key failed: %s\n%s", err, output)
+	}
+
+	return nil
+}
+
+// fingerprintOf resolves id - a key ID or, per KeyID's own doc, already a
+// full fingerprint - to its full fingerprint by looking it up on the
+// public keyring.
+func (m *GnupgKeyringManager) fingerprintOf(id KeyID) (string, error) {
+	keys, err := m.ListKeys(PublicKeyring)
+	if err != nil {
+		return "", err
+	}
+
+	for _, k := range keys {
+		if k.ID == id || KeyID(k.Fingerprint) == id {
+			return k.Fingerprint, nil
+		}
+	}
+
+	return "", fmt.Errorf("key %s not found", id)
+}
+
+// SignFile implements KeyringManager.
+func (m *GnupgKeyringManager) SignFile(path, outputPath string, id KeyID) error {
+	cmd, err := m.command("--local-user", string(id), "--detach-sign", "--armor", "-o", outputPath, path)
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gpg --detach-sign failed: %s\n%s", err, output)
+	}
+
+	return nil
+}
+
+// VerifyFile implements KeyringManager.
+func (m *GnupgKeyringManager) VerifyFile(path, signaturePath string) error {
+	cmd, err := m.command("--verify", signaturePath, path)
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gpg --verify failed: %s\n%s", err, output)
+	}
+
+	return nil
+}
+
+var _ KeyringManager = (*GnupgKeyringManager)(nil)
+
+// GoKeyringManager is a pure-Go KeyringManager built on
+// golang.org/x/crypto/openpgp, for environments where shelling out to gpg
+// isn't available.
+type GoKeyringManager struct {
+	entities openpgp.EntityList
+}
+
+// NewGoKeyringManager returns an empty pure-Go KeyringManager.
+func NewGoKeyringManager() *GoKeyringManager {
+	return &GoKeyringManager{}
+}
+
+// GenerateKey implements KeyringManager.
+func (m *GoKeyringManager) GenerateKey(params KeyGenParams) (KeyID, error) {
+	entity, err := openpgp.NewEntity(params.Name, params.Comment, params.Email, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate key: %s", err)
+	}
+
+	m.entities = append(m.entities, entity)
+
+	return KeyID(entity.PrimaryKey.KeyIdString()), nil
+}
+
+// ListKeys implements KeyringManager.
+func (m *GoKeyringManager) ListKeys(keyring KeyringType) ([]KeyInfo, error) {
+	var keys []KeyInfo
+
+	for _, entity := range m.entities {
+		if keyring == SecretKeyring && entity.PrivateKey == nil {
+			continue
+		}
+
+		keys = append(keys, KeyInfo{
+			ID:          KeyID(entity.PrimaryKey.KeyIdString()),
+			Fingerprint: fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint),
+			UserID:      primaryIdentityName(entity),
+			CreatedAt:   entity.PrimaryKey.CreationTime.String(),
+		})
+	}
+
+	return keys, nil
+}
+
+// primaryIdentityName returns the first (by iteration order, which for a
+// single-identity entity is the only one) identity name on entity, or "" if
+// it has none.
+func primaryIdentityName(entity *openpgp.Entity) string {
+	for name := range entity.Identities {
+		return name
+	}
+	return ""
+}
+
+func (m *GoKeyringManager) find(id KeyID) (*openpgp.Entity, error) {
+	for _, entity := range m.entities {
+		if KeyID(entity.PrimaryKey.KeyIdString()) == id || KeyID(fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)) == id {
+			return entity, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key %s not found", id)
+}
+
+// ExportKey implements KeyringManager.
+func (m *GoKeyringManager) ExportKey(id KeyID, armored bool) ([]byte, error) {
+	entity, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	if !armored {
+		if err := entity.Serialize(&buf); err != nil {
+			return nil, fmt.Errorf("unable to export key %s: %s", id, err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to export key %s: %s", id, err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		return nil, fmt.Errorf("unable to export key %s: %s", id, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("unable to export key %s: %s", id, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ImportKey implements KeyringManager.
+func (m *GoKeyringManager) ImportKey(r io.Reader) ([]KeyID, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read key: %s", err)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("unable to import key: %s", err)
+		}
+	}
+
+	var imported []KeyID
+	for _, entity := range entities {
+		m.entities = append(m.entities, entity)
+		imported = append(imported, KeyID(entity.PrimaryKey.KeyIdString()))
+	}
+
+	return imported, nil
+}
+
+// DeleteKey implements KeyringManager.
+func (m *GoKeyringManager) DeleteKey(id KeyID) error {
+	for i, entity := range m.entities {
+		if KeyID(entity.PrimaryKey.KeyIdString()) == id || KeyID(fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)) == id {
+			m.entities = append(m.entities[:i], m.entities[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("key %s not found", id)
+}
+
+// SignFile implements KeyringManager.
+func (m *GoKeyringManager) SignFile(path, outputPath string, id KeyID) error {
+	entity, err := m.find(id)
+	if err != nil {
+		return err
+	}
+	if entity.PrivateKey == nil {
+		return fmt.Errorf("key %s has no private key available for signing", id)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to sign %s: %s", path, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to sign %s: %s", path, err)
+	}
+	defer out.Close()
+
+	if err := openpgp.ArmoredDetachSign(out, entity, in, nil); err != nil {
+		return fmt.Errorf("unable to sign %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// VerifyFile implements KeyringManager.
+func (m *GoKeyringManager) VerifyFile(path, signaturePath string) error {
+	signed, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to verify %s: %s", path, err)
+	}
+	defer signed.Close()
+
+	signature, err := os.Open(signaturePath)
+	if err != nil {
+		return fmt.Errorf("unable to verify %s: %s", path, err)
+	}
+	defer signature.Close()
+
+	if _, err := openpgp.CheckArmoredDetachedSignature(m.entities, signed, signature); err != nil {
+		return fmt.Errorf("unable to verify %s: %s", path, err)
+	}
+
+	return nil
+}
+
+var _ KeyringManager = (*GoKeyringManager)(nil)
@@ -0,0 +1,104 @@
+package pgp
+
+import (
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type KeyringManagerSuite struct {
+	manager *GnupgKeyringManager
+	homedir string
+}
+
+var _ = Suite(&KeyringManagerSuite{})
+
+func (s *KeyringManagerSuite) SetUpTest(c *C) {
+	finder := GPGDefaultFinder()
+	if _, _, err := finder.FindGPG(); err != nil {
+		c.Skip(err.Error())
+	}
+
+	s.homedir = c.MkDir()
+	s.manager = NewGnupgKeyringManager(finder)
+	s.manager.SetHomedir(s.homedir)
+}
+
+func (s *KeyringManagerSuite) TestGenerateListExportDelete(c *C) {
+	id, err := s.manager.GenerateKey(KeyGenParams{
+		Name:       "Aptly Test",
+		Email:      "test@example.com",
+		KeyType:    "rsa",
+		KeyLength:  1024,
+		Passphrase: "",
+		ExpireDate: "0",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(id, Not(Equals), KeyID(""))
+
+	keys, err := s.manager.ListKeys(PublicKeyring)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 1)
+	c.Assert(keys[0].ID, Equals, id)
+	c.Assert(keys[0].UserID, Equals, "Aptly Test <test@example.com>")
+
+	armored, err := s.manager.ExportKey(id, true)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(armored), "BEGIN PGP PUBLIC KEY BLOCK"), Equals, true)
+
+	c.Assert(s.manager.DeleteKey(id), IsNil)
+
+	keys, err = s.manager.ListKeys(PublicKeyring)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+}
+
+func (s *KeyringManagerSuite) TestImportKey(c *C) {
+	id, err := s.manager.GenerateKey(KeyGenParams{Name: "Aptly Import", KeyType: "rsa", KeyLength: 1024})
+	c.Assert(err, IsNil)
+
+	armored, err := s.manager.ExportKey(id, true)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.manager.DeleteKey(id), IsNil)
+
+	otherHomedir := c.MkDir()
+	other := NewGnupgKeyringManager(GPGDefaultFinder())
+	other.SetHomedir(otherHomedir)
+
+	f, err := os.CreateTemp(otherHomedir, "key-*.asc")
+	c.Assert(err, IsNil)
+	_, err = f.Write(armored)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	in, err := os.Open(f.Name())
+	c.Assert(err, IsNil)
+	defer in.Close()
+
+	imported, err := other.ImportKey(in)
+	c.Assert(err, IsNil)
+	c.Assert(imported, DeepEquals, []KeyID{id})
+}
+
+// fixedVersionFinder wraps a real GPGFinder but reports a caller-chosen
+// FindGPGVersion result, so GenerateKey's version gate can be exercised
+// without needing an actual GPG1 binary on $PATH.
+type fixedVersionFinder struct {
+	GPGFinder
+	version GPGVersion
+}
+
+func (f *fixedVersionFinder) FindGPGVersion(cmd string) (GPGVersion, error) {
+	return f.version, nil
+}
+
+func (s *KeyringManagerSuite) TestGenerateKeyRejectsOldGPG(c *C) {
+	finder := &fixedVersionFinder{GPGFinder: GPGDefaultFinder(), version: GPGVersion{Major: 1, Minor: 4, Patch: 23}}
+	manager := NewGnupgKeyringManager(finder)
+	manager.SetHomedir(s.homedir)
+
+	_, err := manager.GenerateKey(KeyGenParams{Name: "Too Old"})
+	c.Assert(err, ErrorMatches, "GenerateKey requires GnuPG >= 2.1.*")
+}
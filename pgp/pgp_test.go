@@ -0,0 +1,14 @@
+package pgp
+
+import (
+	"testing"
+
+	"gopkg.in/check.v1"
+)
+
+// Test is the gocheck entrypoint: without it, none of this package's
+// Suites are ever registered with `go test`, which silently reports
+// "[no tests to run]" no matter how many Suite()s exist.
+func Test(t *testing.T) {
+	check.TestingT(t)
+}